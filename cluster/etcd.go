@@ -0,0 +1,167 @@
+// Package cluster lets multiple ChatServer processes share one chatroom by
+// registering themselves in etcd and fanning broadcast messages out over a
+// shared watch prefix.
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	nodePrefix      = "/chat/nodes/"
+	broadcastPrefix = "/chat/broadcast/"
+	clientPrefix    = "/chat/clients/"
+	leaseTTLSeconds = 10
+
+	// broadcastTTLSeconds bounds how long a published message's key lives in
+	// etcd before it expires on its own; watchers only need to see it once,
+	// so there's no reason for it to outlive a slow watcher by much.
+	broadcastTTLSeconds = 30
+)
+
+// BroadcastMessage is the payload PUT to broadcastPrefix so every node
+// watching it can fan the message out to its own locally connected clients.
+// Content is the raw message text, not pre-formatted with SenderName, so a
+// watcher can both display it and store it in its own history store as-is.
+type BroadcastMessage struct {
+	SenderID   string    `json:"senderID"`
+	SenderName string    `json:"senderName"`
+	Room       string    `json:"room"`
+	Content    string    `json:"content"`
+	OriginNode string    `json:"originNode"`
+	TS         time.Time `json:"ts"`
+}
+
+// Cluster registers a ChatServer node in etcd under a keepalive lease and
+// fans SendMessage broadcasts out to every other node sharing the cluster.
+type Cluster struct {
+	NodeID string
+
+	client  *clientv3.Client
+	leaseID clientv3.LeaseID
+}
+
+// New dials etcd, registers NodeID under a keepalive lease at
+// /chat/nodes/<nodeID>, and returns a Cluster ready to Publish and Watch.
+func New(nodeID string, endpoints []string) (*Cluster, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to etcd: %w", err)
+	}
+
+	c := &Cluster{NodeID: nodeID, client: cli}
+	if err := c.registerNode(); err != nil {
+		cli.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// registerNode grants a lease, PUTs the node's presence key under it, and
+// starts the background keepalive that renews the lease until Close.
+func (c *Cluster) registerNode() error {
+	lease, err := c.client.Grant(context.Background(), leaseTTLSeconds)
+	if err != nil {
+		return fmt.Errorf("granting lease: %w", err)
+	}
+	c.leaseID = lease.ID
+
+	key := nodePrefix + c.NodeID
+	if _, err := c.client.Put(context.Background(), key, time.Now().Format(time.RFC3339), clientv3.WithLease(lease.ID)); err != nil {
+		return fmt.Errorf("registering node: %w", err)
+	}
+
+	keepAlive, err := c.client.KeepAlive(context.Background(), lease.ID)
+	if err != nil {
+		return fmt.Errorf("starting keepalive: %w", err)
+	}
+	go func() {
+		for range keepAlive {
+			// etcd client renews the lease automatically; nothing to do per-tick.
+		}
+		log.Printf("⚠️ etcd lease for node %s expired", c.NodeID)
+	}()
+
+	return nil
+}
+
+// Publish fans a room message out to every node watching broadcastPrefix.
+// The key is granted its own short-lived lease (distinct from the node's
+// keepalive lease) so it expires on its own shortly after every watcher has
+// had a chance to see it, instead of accumulating in etcd forever.
+func (c *Cluster) Publish(senderID, senderName, room, content string) error {
+	msg := BroadcastMessage{
+		SenderID:   senderID,
+		SenderName: senderName,
+		Room:       room,
+		Content:    content,
+		OriginNode: c.NodeID,
+		TS:         time.Now(),
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	lease, err := c.client.Grant(context.Background(), broadcastTTLSeconds)
+	if err != nil {
+		return fmt.Errorf("granting broadcast lease: %w", err)
+	}
+
+	key := fmt.Sprintf("%s%s-%d", broadcastPrefix, c.NodeID, time.Now().UnixNano())
+	_, err = c.client.Put(context.Background(), key, string(data), clientv3.WithLease(lease.ID))
+	return err
+}
+
+// Watch subscribes to broadcastPrefix and invokes fn for every message that
+// did not originate on this node, so the caller can fan it out locally
+// without looping it back to the node that published it.
+func (c *Cluster) Watch(fn func(BroadcastMessage)) {
+	watchChan := c.client.Watch(context.Background(), broadcastPrefix, clientv3.WithPrefix())
+	go func() {
+		for resp := range watchChan {
+			for _, ev := range resp.Events {
+				if ev.Type != clientv3.EventTypePut {
+					continue
+				}
+				var msg BroadcastMessage
+				if err := json.Unmarshal(ev.Kv.Value, &msg); err != nil {
+					log.Printf("⚠️ Bad broadcast payload: %v", err)
+					continue
+				}
+				if msg.OriginNode == c.NodeID {
+					continue // already delivered locally when it was sent
+				}
+				fn(msg)
+			}
+		}
+	}()
+}
+
+// RegisterClient records that clientID is connected to this node at
+// /chat/clients/<clientID>, tied to the node's lease so presence expires
+// automatically if the node disappears without calling UnregisterClient.
+func (c *Cluster) RegisterClient(clientID string) error {
+	_, err := c.client.Put(context.Background(), clientPrefix+clientID, c.NodeID, clientv3.WithLease(c.leaseID))
+	return err
+}
+
+// UnregisterClient removes a client's presence entry when it leaves.
+func (c *Cluster) UnregisterClient(clientID string) error {
+	_, err := c.client.Delete(context.Background(), clientPrefix+clientID)
+	return err
+}
+
+// Close releases the underlying etcd client.
+func (c *Cluster) Close() error {
+	return c.client.Close()
+}
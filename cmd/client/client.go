@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message types routed by the server's SendMessage.
+const (
+	MsgTypeChat      = "msg"
+	MsgTypeJoinRoom  = "join_room"
+	MsgTypeLeaveRoom = "leave_room"
+	MsgTypePM        = "pm"
+	MsgTypeList      = "list"
+	MsgTypeNick      = "nick"
+)
+
+// Message structure matching server. SessionToken must be the token minted
+// for ClientID at Join; the server rejects any RPC where it doesn't match.
+type Message struct {
+	ClientID     string
+	Content      string
+	Type         string
+	Target       string
+	TS           time.Time
+	SessionToken string
+}
+
+// JoinRequest structure. Token is validated by the server, which may derive
+// a different, authoritative ClientID from it. SessionToken is only used
+// (and required) when this struct is reused to call Leave.
+type JoinRequest struct {
+	ClientID     string
+	Token        string
+	SessionToken string
+}
+
+// JoinResponse structure
+type JoinResponse struct {
+	ClientID     string
+	History      []Message
+	SessionToken string
+}
+
+// HistoryRequest asks the server for scrollback on a room.
+type HistoryRequest struct {
+	ClientID string
+	Room     string
+	N        int
+}
+
+// HistoryResponse carries the requested scrollback, oldest first.
+type HistoryResponse struct {
+	Messages []Message
+}
+
+// rpcAddr is the RPC control connection; pushAddr is the second, long-lived
+// connection the server streams broadcasts down.
+const (
+	rpcAddr  = "localhost:1234"
+	pushAddr = "localhost:1235"
+)
+
+// pushFrame is the length-prefixed JSON payload delivered on the push connection.
+type pushFrame struct {
+	Content string
+}
+
+// pushRegistration is the first frame sent on a new push connection so the
+// server can associate it with an already-joined client. SessionToken is the
+// credential minted at Join; the server refuses to attach without it.
+type pushRegistration struct {
+	ClientID     string
+	SessionToken string
+}
+
+// Client ID generator and connection settings
+var (
+	clientID           string
+	sessionToken       string
+	mu                 sync.Mutex
+	quit               chan bool
+	useTLS             bool
+	insecureSkipVerify bool
+)
+
+// dial opens addr as plain TCP, or as TLS when useTLS is set.
+func dial(addr string) (net.Conn, error) {
+	if !useTLS {
+		return net.Dial("tcp", addr)
+	}
+	return tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: insecureSkipVerify})
+}
+
+// listenForMessages holds a long-lived push connection to the server and
+// blocks on frame reads, printing each message as it arrives. If the
+// connection drops it is redialed with exponential backoff.
+func listenForMessages(clientID string) {
+	backoff := 500 * time.Millisecond
+	const maxBackoff = 10 * time.Second
+
+	for {
+		select {
+		case <-quit:
+			return
+		default:
+		}
+
+		conn, err := dial(pushAddr)
+		if err != nil {
+			log.Printf("❌ Push stream connect failed: %v (retrying in %s)", err, backoff)
+			time.Sleep(backoff)
+			if backoff < maxBackoff {
+				backoff *= 2
+			}
+			continue
+		}
+
+		if err := writeFrame(conn, pushRegistration{ClientID: clientID, SessionToken: sessionToken}); err != nil {
+			log.Printf("❌ Push stream registration failed: %v", err)
+			conn.Close()
+			continue
+		}
+
+		start := time.Now()
+		recvLoop(conn)
+		conn.Close()
+
+		// A connection that stayed up for a while resets the backoff; one the
+		// server closes right away (e.g. a rejected registration) keeps growing it.
+		if time.Since(start) > maxBackoff {
+			backoff = 500 * time.Millisecond
+		}
+		time.Sleep(backoff)
+		if backoff < maxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// recvLoop blocks reading frames off conn until it errors or is closed.
+func recvLoop(conn net.Conn) {
+	for {
+		var frame pushFrame
+		if err := readFrame(conn, &frame); err != nil {
+			if err != io.EOF {
+				log.Printf("❌ Error receiving message: %v", err)
+			}
+			return
+		}
+		fmt.Printf("\n%s\n", frame.Content)
+		fmt.Print("You: ")
+	}
+}
+
+// parseCommand translates a leading "/join", "/leave", "/pm", "/nick" or "/who" into
+// its typed Message, or treats the line as a plain chat message otherwise.
+// Returns nil if a command was recognized but malformed.
+func parseCommand(clientID, line string) *Message {
+	switch {
+	case strings.HasPrefix(line, "/join "):
+		room := strings.TrimSpace(strings.TrimPrefix(line, "/join "))
+		if room == "" {
+			return nil
+		}
+		return &Message{ClientID: clientID, Type: MsgTypeJoinRoom, Target: room, SessionToken: sessionToken}
+
+	case line == "/leave":
+		return &Message{ClientID: clientID, Type: MsgTypeLeaveRoom, SessionToken: sessionToken}
+
+	case strings.HasPrefix(line, "/pm "):
+		rest := strings.TrimPrefix(line, "/pm ")
+		parts := strings.SplitN(rest, " ", 2)
+		if len(parts) < 2 || parts[0] == "" {
+			return nil
+		}
+		return &Message{ClientID: clientID, Type: MsgTypePM, Target: parts[0], Content: parts[1], SessionToken: sessionToken}
+
+	case strings.HasPrefix(line, "/nick "):
+		nick := strings.TrimSpace(strings.TrimPrefix(line, "/nick "))
+		if nick == "" {
+			return nil
+		}
+		return &Message{ClientID: clientID, Type: MsgTypeNick, Target: nick, SessionToken: sessionToken}
+
+	case line == "/who":
+		return &Message{ClientID: clientID, Type: MsgTypeList, SessionToken: sessionToken}
+
+	default:
+		return &Message{ClientID: clientID, Type: MsgTypeChat, Content: line, SessionToken: sessionToken}
+	}
+}
+
+// printHistory prints backlog messages in the same format the push stream uses.
+func printHistory(messages []Message) {
+	for _, m := range messages {
+		fmt.Printf("[%s]: %s\n", m.ClientID, m.Content)
+	}
+}
+
+// handleUserInput reads user input and sends messages
+func handleUserInput(client *rpc.Client, clientID string, reader *bufio.Reader) {
+	for {
+		fmt.Print("You: ")
+		msg, _ := reader.ReadString('\n')
+		msg = strings.TrimSpace(msg)
+
+		if msg == "" {
+			continue
+		}
+
+		if msg == "exit" {
+			fmt.Println("👋 Goodbye!")
+			// Notify server of departure
+			var res JoinResponse
+			client.Call("ChatServer.Leave", &JoinRequest{ClientID: clientID, SessionToken: sessionToken}, &res)
+			quit <- true
+			break
+		}
+
+		if msg == "/history" {
+			var res HistoryResponse
+			if err := client.Call("ChatServer.GetHistory", &HistoryRequest{ClientID: clientID}, &res); err != nil {
+				log.Printf("⚠️ Error fetching history: %v", err)
+				continue
+			}
+			printHistory(res.Messages)
+			continue
+		}
+
+		message := parseCommand(clientID, msg)
+		if message == nil {
+			fmt.Println("⚠️ Usage: /join #room | /pm <user> <msg> | /nick <name> | /who | /history")
+			continue
+		}
+
+		var response Message
+		err := client.Call("ChatServer.SendMessage", message, &response)
+		if err != nil {
+			log.Printf("⚠️ Error sending message: %v", err)
+			continue
+		}
+
+		switch message.Type {
+		case MsgTypeList:
+			fmt.Printf("👥 Members: %s\n", response.Content)
+		case MsgTypeJoinRoom, MsgTypeLeaveRoom, MsgTypeNick:
+			fmt.Printf("✅ %s\n", response.Content)
+		}
+	}
+}
+
+// writeFrame writes a length-prefixed JSON encoding of v.
+func writeFrame(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readFrame reads a length-prefixed JSON frame written by writeFrame into v.
+func readFrame(r io.Reader, v interface{}) error {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func main() {
+	flag.BoolVar(&useTLS, "tls", false, "use TLS to connect to the server")
+	flag.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "skip TLS certificate verification (dev only)")
+	token := flag.String("token", "", "auth token presented to Join")
+	flag.Parse()
+
+	// Generate unique client ID; the server may override it based on Token
+	clientID = fmt.Sprintf("User_%d", time.Now().UnixNano()%100000)
+	quit = make(chan bool)
+
+	// Connect to server
+	conn, err := dial(rpcAddr)
+	if err != nil {
+		log.Fatal("❌ Error connecting to server:", err)
+	}
+	client := rpc.NewClient(conn)
+	defer client.Close()
+
+	// Join the chatroom
+	var res JoinResponse
+	err = client.Call("ChatServer.Join", &JoinRequest{ClientID: clientID, Token: *token}, &res)
+	if err != nil {
+		log.Fatal("❌ Error joining chatroom:", err)
+	}
+	clientID = res.ClientID
+	sessionToken = res.SessionToken
+
+	fmt.Println("✅ Connected to chatroom!")
+	fmt.Printf("📝 Your ID: %s\n", clientID)
+	if len(res.History) > 0 {
+		fmt.Println("--- backlog ---")
+		printHistory(res.History)
+		fmt.Println("---------------")
+	}
+	fmt.Println("Type your message below (type 'exit' to quit):")
+
+	// Start goroutine to maintain the push stream and print incoming messages
+	go listenForMessages(clientID)
+
+	// Handle user input in main goroutine
+	reader := bufio.NewReader(os.Stdin)
+	handleUserInput(client, clientID, reader)
+}
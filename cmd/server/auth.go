@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Authenticator validates a Join token and returns the authoritative ClientID
+// the server should trust, rather than whatever ClientID the client claims.
+type Authenticator interface {
+	Authenticate(token string) (clientID string, err error)
+}
+
+// staticTokenAuthenticator validates tokens against a file of "token:clientID"
+// lines, for provisioning a fixed set of users without a JWT issuer.
+type staticTokenAuthenticator struct {
+	tokens map[string]string
+}
+
+// newStaticTokenAuthenticator loads "token:clientID" pairs from path, one per
+// line; blank lines and lines starting with # are skipped.
+func newStaticTokenAuthenticator(path string) (*staticTokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening token file: %w", err)
+	}
+	defer f.Close()
+
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed token line: %q", line)
+		}
+		tokens[parts[0]] = parts[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &staticTokenAuthenticator{tokens: tokens}, nil
+}
+
+func (a *staticTokenAuthenticator) Authenticate(token string) (string, error) {
+	clientID, ok := a.tokens[token]
+	if !ok {
+		return "", errors.New("unknown token")
+	}
+	return clientID, nil
+}
+
+// jwtClaims is the subset of JWT claims Authenticate cares about.
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+}
+
+// jwtAuthenticator validates HMAC-SHA256-signed JWTs and trusts their "sub"
+// claim as the ClientID.
+type jwtAuthenticator struct {
+	secret []byte
+}
+
+func newJWTAuthenticator(secret string) *jwtAuthenticator {
+	return &jwtAuthenticator{secret: []byte(secret)}
+}
+
+func (a *jwtAuthenticator) Authenticate(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return "", errors.New("invalid signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decoding claims: %w", err)
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("parsing claims: %w", err)
+	}
+	if claims.Sub == "" {
+		return "", errors.New("missing sub claim")
+	}
+	if claims.Exp != 0 && time.Now().Unix() > claims.Exp {
+		return "", errors.New("token expired")
+	}
+
+	return claims.Sub, nil
+}
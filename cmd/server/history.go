@@ -0,0 +1,170 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// historyReplayCount is how many prior messages a client sees on Join.
+const historyReplayCount = 20
+
+// HistoryStore persists chat messages per room so a client joining mid-session
+// can replay backlog, and so clients can request explicit scrollback.
+type HistoryStore interface {
+	Append(room string, m Message) error
+	Tail(room string, n int) ([]Message, error)
+	Since(room string, ts time.Time) ([]Message, error)
+}
+
+// memoryHistoryStore is the default HistoryStore: an in-memory ring buffer
+// capped per room, lost on restart.
+type memoryHistoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	messages map[string][]Message
+}
+
+// newMemoryHistoryStore returns a HistoryStore that keeps up to capacity
+// messages per room in memory.
+func newMemoryHistoryStore(capacity int) *memoryHistoryStore {
+	return &memoryHistoryStore{
+		capacity: capacity,
+		messages: make(map[string][]Message),
+	}
+}
+
+func (s *memoryHistoryStore) Append(room string, m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgs := append(s.messages[room], m)
+	if len(msgs) > s.capacity {
+		msgs = msgs[len(msgs)-s.capacity:]
+	}
+	s.messages[room] = msgs
+	return nil
+}
+
+func (s *memoryHistoryStore) Tail(room string, n int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgs := s.messages[room]
+	if n > len(msgs) {
+		n = len(msgs)
+	}
+	out := make([]Message, n)
+	copy(out, msgs[len(msgs)-n:])
+	return out, nil
+}
+
+func (s *memoryHistoryStore) Since(room string, ts time.Time) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Message
+	for _, m := range s.messages[room] {
+		if m.TS.After(ts) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// sqliteHistoryStore persists messages to a SQLite database so history
+// survives server restarts.
+type sqliteHistoryStore struct {
+	db *sql.DB
+}
+
+// newSQLiteHistoryStore opens (creating if needed) a SQLite database at path
+// and ensures the messages table and its (room, ts) index exist.
+func newSQLiteHistoryStore(path string) (*sqliteHistoryStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite history store: %w", err)
+	}
+
+	const schema = `
+	CREATE TABLE IF NOT EXISTS messages (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		room    TEXT NOT NULL,
+		sender  TEXT NOT NULL,
+		content TEXT NOT NULL,
+		ts      INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_messages_room_ts ON messages(room, ts);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+
+	return &sqliteHistoryStore{db: db}, nil
+}
+
+func (s *sqliteHistoryStore) Append(room string, m Message) error {
+	_, err := s.db.Exec(
+		`INSERT INTO messages(room, sender, content, ts) VALUES (?, ?, ?, ?)`,
+		room, m.ClientID, m.Content, m.TS.UnixNano(),
+	)
+	return err
+}
+
+func (s *sqliteHistoryStore) Tail(room string, n int) ([]Message, error) {
+	rows, err := s.db.Query(
+		`SELECT sender, content, ts FROM messages WHERE room = ? ORDER BY ts DESC LIMIT ?`,
+		room, n,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out, err := scanMessages(rows, room)
+	if err != nil {
+		return nil, err
+	}
+	// The query returns newest-first; Tail is expected chronological.
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+func (s *sqliteHistoryStore) Since(room string, ts time.Time) ([]Message, error) {
+	rows, err := s.db.Query(
+		`SELECT sender, content, ts FROM messages WHERE room = ? AND ts > ? ORDER BY ts ASC`,
+		room, ts.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows, room)
+}
+
+func scanMessages(rows *sql.Rows, room string) ([]Message, error) {
+	var out []Message
+	for rows.Next() {
+		var m Message
+		var tsNano int64
+		if err := rows.Scan(&m.ClientID, &m.Content, &tsNano); err != nil {
+			return nil, err
+		}
+		m.Type = MsgTypeChat
+		m.Target = room
+		m.TS = time.Unix(0, tsNano)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqliteHistoryStore) Close() error {
+	return s.db.Close()
+}
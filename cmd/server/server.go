@@ -0,0 +1,891 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"real-time-chat/cluster"
+)
+
+// rateLimitPerSecond and rateLimitBurst bound how fast a single client may
+// call SendMessage before it's throttled instead of broadcast.
+// maxRateLimitViolations is how many throttled SendMessage calls a client can
+// rack up before it's dropped as persistently misbehaving.
+const (
+	rateLimitPerSecond     = 5
+	rateLimitBurst         = 10
+	maxRateLimitViolations = 10
+)
+
+// Client represents a connected client
+type Client struct {
+	id         string
+	nick       string
+	room       string
+	sendChan   chan string
+	limiter    *rate.Limiter
+	violations int // consecutive rate-limit rejections, protected by ChatServer.mu
+
+	pushMu   sync.Mutex
+	pushConn net.Conn
+	pushDone chan struct{} // closed to stop the previous drain loop once a newer push connection attaches
+}
+
+// RateLimitError is returned by SendMessage when a client exceeds its
+// token-bucket rate limit; the message is not broadcast.
+type RateLimitError struct {
+	ClientID string
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s", e.ClientID)
+}
+
+// displayName returns the client's nickname if one was set via /nick,
+// otherwise falls back to its ID.
+func (cl *Client) displayName() string {
+	if cl.nick != "" {
+		return cl.nick
+	}
+	return cl.id
+}
+
+// mintSessionToken derives a per-session credential for clientID from a
+// random nonce, HMAC-signed with sessionSecret so it can't be forged or
+// guessed from the (public) clientID alone.
+func (c *ChatServer) mintSessionToken(clientID string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating session nonce: %w", err)
+	}
+	nonceStr := base64.RawURLEncoding.EncodeToString(nonce)
+
+	mac := hmac.New(sha256.New, c.sessionSecret)
+	mac.Write([]byte(clientID + ":" + nonceStr))
+	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return nonceStr + "." + sig, nil
+}
+
+// verifySessionToken reports whether token was minted by mintSessionToken
+// for clientID, i.e. whether the RPC caller is the same one who joined as
+// clientID rather than just naming it.
+func (c *ChatServer) verifySessionToken(clientID, token string) bool {
+	nonceStr, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha256.New, c.sessionSecret)
+	mac.Write([]byte(clientID + ":" + nonceStr))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+// ChatServer manages all connected clients, the rooms they belong to, and broadcasts
+type ChatServer struct {
+	mu      sync.Mutex // Protects clients and rooms
+	clients map[string]*Client
+	rooms   map[string]map[string]*Client
+	nextID  int
+
+	// NodeID identifies this process in the cluster; Cluster is nil when the
+	// server is run standalone (no --etcd-endpoints given).
+	NodeID  string
+	Cluster *cluster.Cluster
+
+	// History backs replay-on-join and the GetHistory RPC.
+	History HistoryStore
+
+	// Auth validates Join tokens; nil means the server accepts any ClientID
+	// a client claims (no --token-file or --jwt-secret configured).
+	Auth Authenticator
+
+	// AdminToken gates the Kick RPC; empty disables Kick entirely, since an
+	// unauthenticated eviction endpoint is itself a denial-of-service vector.
+	AdminToken string
+
+	// sessionSecret signs the per-session tokens minted at Join, so every
+	// later RPC can confirm the caller was the one who actually joined as
+	// ClientID instead of just naming it. Generated once in main.
+	sessionSecret []byte
+}
+
+// defaultRoom is where every client lands after Join.
+const defaultRoom = "#lobby"
+
+// Message types routed by SendMessage.
+const (
+	MsgTypeChat      = "msg"
+	MsgTypeJoinRoom  = "join_room"
+	MsgTypeLeaveRoom = "leave_room"
+	MsgTypePM        = "pm"
+	MsgTypeList      = "list"
+	MsgTypeNick      = "nick"
+)
+
+// Message structure for RPC communication. Type selects how SendMessage
+// routes it ("msg" if empty); Target holds a room name for room messages/
+// listings or a recipient ClientID for pm. SessionToken must be the token
+// minted for ClientID at Join, since ClientID and nicks are public
+// (broadcast in join/leave/list) and otherwise trivial to impersonate.
+type Message struct {
+	ClientID     string
+	Content      string
+	Type         string
+	Target       string
+	TS           time.Time
+	SessionToken string
+}
+
+// JoinRequest represents a client joining the chat. Token is validated
+// against the server's Authenticator, which derives the authoritative
+// ClientID rather than trusting the one the client supplies. SessionToken is
+// unused by Join itself but is required (and checked) when this struct is
+// reused to call Leave.
+type JoinRequest struct {
+	ClientID     string
+	Token        string
+	SessionToken string
+}
+
+// KickRequest names a client to forcibly evict, e.g. after repeated
+// rate-limit violations. AdminToken must match the server's configured
+// admin token, since this RPC is otherwise a trivial eviction vector for any
+// TCP peer.
+type KickRequest struct {
+	ClientID   string
+	AdminToken string
+}
+
+// JoinResponse confirms the join and carries recent backlog for the client to
+// print before entering its input loop. SessionToken must be echoed back on
+// SendMessage, Leave and the push registration; GetHistory does not take it.
+type JoinResponse struct {
+	ClientID     string
+	History      []Message
+	SessionToken string
+}
+
+// HistoryRequest asks for scrollback on a room.
+type HistoryRequest struct {
+	ClientID string
+	Room     string
+	N        int
+}
+
+// HistoryResponse carries the requested scrollback, oldest first.
+type HistoryResponse struct {
+	Messages []Message
+}
+
+// pushAddr is where clients open their second, long-lived connection on which
+// the server streams framed messages as they're broadcast.
+const pushAddr = ":1235"
+
+// pushFrame is the length-prefixed JSON payload delivered on the push connection.
+type pushFrame struct {
+	Content string
+}
+
+// pushRegistration is the first frame a client sends on a new push
+// connection so the server can associate it with an already-joined client.
+// SessionToken must match the token minted for ClientID at Join, since
+// ClientID alone is public (broadcast in join/leave/list) and otherwise lets
+// any TCP peer hijack a victim's push stream.
+type pushRegistration struct {
+	ClientID     string
+	SessionToken string
+}
+
+// Join RPC method - called when client connects
+func (c *ChatServer) Join(req *JoinRequest, res *JoinResponse) error {
+	clientID := req.ClientID
+	if c.Auth != nil {
+		authedID, err := c.Auth.Authenticate(req.Token)
+		if err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+		clientID = authedID
+	}
+
+	c.mu.Lock()
+	client := &Client{
+		id:       clientID,
+		sendChan: make(chan string, 10), // buffered channel
+		limiter:  rate.NewLimiter(rateLimitPerSecond, rateLimitBurst),
+	}
+	c.clients[clientID] = client
+	c.addToRoomLocked(client, defaultRoom)
+	numClients := len(c.clients)
+	c.mu.Unlock()
+
+	sessionToken, err := c.mintSessionToken(clientID)
+	if err != nil {
+		c.dropClient(clientID)
+		return fmt.Errorf("minting session token: %w", err)
+	}
+
+	res.ClientID = clientID
+	res.SessionToken = sessionToken
+	log.Printf("✅ User %s joined! Total clients: %d", clientID, numClients)
+
+	if c.Cluster != nil {
+		if err := c.Cluster.RegisterClient(clientID); err != nil {
+			log.Printf("⚠️ Failed to register %s in cluster: %v", clientID, err)
+		}
+	}
+
+	// Broadcast join notification to the rest of the lobby
+	joinMsg := fmt.Sprintf("📢 User %s joined", clientID)
+	c.broadcastToRoom(defaultRoom, clientID, joinMsg)
+
+	if history, err := c.History.Tail(defaultRoom, historyReplayCount); err != nil {
+		log.Printf("⚠️ Failed to load history for %s: %v", clientID, err)
+	} else {
+		res.History = history
+	}
+
+	return nil
+}
+
+// Kick RPC method - forcibly evicts a misbehaving client, closing its
+// sendChan and removing it from the clients map. Requires AdminToken to match
+// the server's configured admin token; refuses entirely if none is
+// configured, since Kick is otherwise exploitable by any TCP peer to evict
+// any other user.
+func (c *ChatServer) Kick(req *KickRequest, res *JoinResponse) error {
+	if c.AdminToken == "" || req.AdminToken != c.AdminToken {
+		return errors.New("unauthorized")
+	}
+	c.dropClient(req.ClientID)
+	res.ClientID = req.ClientID
+	return nil
+}
+
+// GetHistory RPC method - explicit scrollback request for a room. Requires
+// ClientID to name a currently-joined client; unlike SendMessage and Leave it
+// checks no session token, so it does not confirm the caller is that client.
+func (c *ChatServer) GetHistory(req *HistoryRequest, res *HistoryResponse) error {
+	c.mu.Lock()
+	_, exists := c.clients[req.ClientID]
+	c.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("client %s not found", req.ClientID)
+	}
+
+	room := req.Room
+	if room == "" {
+		room = defaultRoom
+	}
+	n := req.N
+	if n <= 0 {
+		n = historyReplayCount
+	}
+
+	messages, err := c.History.Tail(room, n)
+	if err != nil {
+		return fmt.Errorf("loading history for %s: %w", room, err)
+	}
+	res.Messages = messages
+	return nil
+}
+
+// SendMessage RPC method - routes by msg.Type, defaulting to a plain room
+// broadcast when Type is empty.
+func (c *ChatServer) SendMessage(msg *Message, res *Message) error {
+	c.mu.Lock()
+	client, exists := c.clients[msg.ClientID]
+	if !exists {
+		c.mu.Unlock()
+		return fmt.Errorf("client %s not found", msg.ClientID)
+	}
+	if !c.verifySessionToken(msg.ClientID, msg.SessionToken) {
+		c.mu.Unlock()
+		return errors.New("invalid session token")
+	}
+	if !client.limiter.Allow() {
+		client.violations++
+		persistent := client.violations >= maxRateLimitViolations
+		c.mu.Unlock()
+		if persistent {
+			log.Printf("🚫 Evicting %s after %d rate-limit violations", msg.ClientID, maxRateLimitViolations)
+			c.dropClient(msg.ClientID)
+		}
+		return &RateLimitError{ClientID: msg.ClientID}
+	}
+	client.violations = 0
+	c.mu.Unlock()
+
+	switch msg.Type {
+	case MsgTypePM:
+		return c.sendPM(msg, res)
+	case MsgTypeList:
+		return c.listMembers(msg, res)
+	case MsgTypeJoinRoom:
+		return c.joinRoom(msg, res)
+	case MsgTypeLeaveRoom:
+		return c.leaveRoom(msg, res)
+	case MsgTypeNick:
+		return c.setNick(msg, res)
+	default:
+		return c.broadcastChat(msg, res)
+	}
+}
+
+// broadcastChat handles the default "msg" type: broadcast to Target room, or
+// the sender's current room if Target is empty.
+func (c *ChatServer) broadcastChat(msg *Message, res *Message) error {
+	log.Printf("📨 Message from %s: %s", msg.ClientID, msg.Content)
+
+	c.mu.Lock()
+	client, exists := c.clients[msg.ClientID]
+	room := msg.Target
+	var name string
+	if exists {
+		if room == "" {
+			room = client.room
+		}
+		name = client.displayName()
+	}
+	c.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("client %s not found", msg.ClientID)
+	}
+
+	broadcastMsg := fmt.Sprintf("[%s]: %s", name, msg.Content)
+	c.broadcastToRoom(room, msg.ClientID, broadcastMsg)
+
+	// Fan out to every other node in the cluster, if clustering is enabled.
+	// Publish the raw content and display name separately rather than the
+	// pre-rendered broadcastMsg, so remote nodes can both display it and
+	// store it in History without double-applying the "[name]: " prefix.
+	if c.Cluster != nil {
+		if err := c.Cluster.Publish(msg.ClientID, name, room, msg.Content); err != nil {
+			log.Printf("⚠️ Failed to publish to cluster: %v", err)
+		}
+	}
+
+	entry := *msg
+	entry.TS = time.Now()
+	if err := c.History.Append(room, entry); err != nil {
+		log.Printf("⚠️ Failed to append history for room %s: %v", room, err)
+	}
+
+	res.ClientID = msg.ClientID
+	res.Content = "message_received"
+	return nil
+}
+
+// sendPM delivers Content to a single client named by Target.
+func (c *ChatServer) sendPM(msg *Message, res *Message) error {
+	c.mu.Lock()
+	sender, senderOK := c.clients[msg.ClientID]
+	target, targetOK := c.clients[msg.Target]
+	var senderName string
+	if senderOK {
+		senderName = sender.displayName()
+	}
+	c.mu.Unlock()
+	if !senderOK {
+		return fmt.Errorf("client %s not found", msg.ClientID)
+	}
+	if !targetOK {
+		return fmt.Errorf("user %s not found", msg.Target)
+	}
+
+	pm := fmt.Sprintf("✉️ [PM from %s]: %s", senderName, msg.Content)
+	select {
+	case target.sendChan <- pm:
+	default:
+		log.Printf("⚠️ Warning: channel full for client %s", target.id)
+	}
+
+	res.ClientID = msg.ClientID
+	res.Content = "pm_sent"
+	return nil
+}
+
+// listMembers returns the member list of Target, or the sender's current
+// room if Target is empty.
+func (c *ChatServer) listMembers(msg *Message, res *Message) error {
+	c.mu.Lock()
+	room := msg.Target
+	if room == "" {
+		if client, ok := c.clients[msg.ClientID]; ok {
+			room = client.room
+		} else {
+			room = defaultRoom
+		}
+	}
+	names := make([]string, 0, len(c.rooms[room]))
+	for _, client := range c.rooms[room] {
+		names = append(names, client.displayName())
+	}
+	c.mu.Unlock()
+
+	res.ClientID = msg.ClientID
+	res.Content = strings.Join(names, ", ")
+	return nil
+}
+
+// joinRoom moves a client into Target, leaving whatever room it was in.
+func (c *ChatServer) joinRoom(msg *Message, res *Message) error {
+	room := msg.Target
+	if room == "" {
+		room = defaultRoom
+	}
+
+	c.mu.Lock()
+	client, exists := c.clients[msg.ClientID]
+	if !exists {
+		c.mu.Unlock()
+		return fmt.Errorf("client %s not found", msg.ClientID)
+	}
+	c.removeFromRoomLocked(client)
+	c.addToRoomLocked(client, room)
+	name := client.displayName()
+	c.mu.Unlock()
+
+	log.Printf("🚪 %s joined room %s", msg.ClientID, room)
+	c.broadcastToRoom(room, msg.ClientID, fmt.Sprintf("📢 %s joined %s", name, room))
+
+	res.ClientID = msg.ClientID
+	res.Content = fmt.Sprintf("joined %s", room)
+	return nil
+}
+
+// leaveRoom removes a client from its current room, returning it to the lobby.
+func (c *ChatServer) leaveRoom(msg *Message, res *Message) error {
+	c.mu.Lock()
+	client, exists := c.clients[msg.ClientID]
+	if !exists {
+		c.mu.Unlock()
+		return fmt.Errorf("client %s not found", msg.ClientID)
+	}
+	oldRoom := client.room
+	c.removeFromRoomLocked(client)
+	c.addToRoomLocked(client, defaultRoom)
+	name := client.displayName()
+	c.mu.Unlock()
+
+	log.Printf("🚪 %s left room %s", msg.ClientID, oldRoom)
+	c.broadcastToRoom(oldRoom, msg.ClientID, fmt.Sprintf("📢 %s left %s", name, oldRoom))
+
+	res.ClientID = msg.ClientID
+	res.Content = fmt.Sprintf("left %s", oldRoom)
+	return nil
+}
+
+// setNick changes a client's display name to Target.
+func (c *ChatServer) setNick(msg *Message, res *Message) error {
+	c.mu.Lock()
+	client, exists := c.clients[msg.ClientID]
+	if exists {
+		client.nick = msg.Target
+	}
+	c.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("client %s not found", msg.ClientID)
+	}
+
+	res.ClientID = msg.ClientID
+	res.Content = fmt.Sprintf("nick set to %s", msg.Target)
+	return nil
+}
+
+// Leave RPC method - called when client disconnects
+func (c *ChatServer) Leave(req *JoinRequest, res *JoinResponse) error {
+	c.mu.Lock()
+	client, exists := c.clients[req.ClientID]
+	if exists && !c.verifySessionToken(req.ClientID, req.SessionToken) {
+		c.mu.Unlock()
+		return errors.New("invalid session token")
+	}
+	var room string
+	if exists {
+		room = client.room
+		c.removeFromRoomLocked(client)
+		delete(c.clients, req.ClientID)
+	}
+	numClients := len(c.clients)
+	c.mu.Unlock()
+
+	if exists {
+		closePushConn(client)
+		close(client.sendChan)
+	}
+
+	if c.Cluster != nil {
+		if err := c.Cluster.UnregisterClient(req.ClientID); err != nil {
+			log.Printf("⚠️ Failed to unregister %s from cluster: %v", req.ClientID, err)
+		}
+	}
+
+	res.ClientID = req.ClientID
+	log.Printf("👋 User %s left! Total clients: %d", req.ClientID, numClients)
+
+	// Broadcast leave notification to the rest of that room
+	leaveMsg := fmt.Sprintf("📢 User %s left", req.ClientID)
+	if room != "" {
+		c.broadcastToRoom(room, req.ClientID, leaveMsg)
+	}
+
+	return nil
+}
+
+// addToRoomLocked adds client to room. Caller must hold c.mu.
+func (c *ChatServer) addToRoomLocked(client *Client, room string) {
+	if c.rooms[room] == nil {
+		c.rooms[room] = make(map[string]*Client)
+	}
+	c.rooms[room][client.id] = client
+	client.room = room
+}
+
+// removeFromRoomLocked removes client from its current room, pruning the
+// room if it's now empty. Caller must hold c.mu.
+func (c *ChatServer) removeFromRoomLocked(client *Client) {
+	if client.room == "" {
+		return
+	}
+	delete(c.rooms[client.room], client.id)
+	if len(c.rooms[client.room]) == 0 {
+		delete(c.rooms, client.room)
+	}
+	client.room = ""
+}
+
+// broadcastToRoom sends a message to every member of room except the sender.
+func (c *ChatServer) broadcastToRoom(room string, senderID string, msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, client := range c.rooms[room] {
+		if id != senderID { // Don't send to self
+			select {
+			case client.sendChan <- msg:
+				// Message sent
+			default:
+				log.Printf("⚠️ Warning: channel full for client %s", id)
+			}
+		}
+	}
+}
+
+// servePush accepts push connections, reads the registering client's ID off
+// the first frame, then hands the connection to that client's drain loop.
+func (c *ChatServer) servePush(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Println("Push listener error:", err)
+			continue
+		}
+		go c.handlePushConn(conn)
+	}
+}
+
+func (c *ChatServer) handlePushConn(conn net.Conn) {
+	var reg pushRegistration
+	if err := readFrame(conn, &reg); err != nil {
+		log.Printf("⚠️ Invalid push registration: %v", err)
+		conn.Close()
+		return
+	}
+
+	c.mu.Lock()
+	client, exists := c.clients[reg.ClientID]
+	c.mu.Unlock()
+	if !exists {
+		conn.Close()
+		return
+	}
+	if !c.verifySessionToken(reg.ClientID, reg.SessionToken) {
+		log.Printf("⚠️ Rejected push registration for %s: invalid session token", reg.ClientID)
+		conn.Close()
+		return
+	}
+
+	// Supersede any previous push connection for this client: close it and
+	// signal its drain loop to stop, so a reconnect can't leave two
+	// goroutines racing as competing consumers of sendChan.
+	client.pushMu.Lock()
+	oldConn := client.pushConn
+	if client.pushDone != nil {
+		close(client.pushDone)
+	}
+	done := make(chan struct{})
+	client.pushDone = done
+	client.pushConn = conn
+	client.pushMu.Unlock()
+	if oldConn != nil {
+		oldConn.Close()
+	}
+	log.Printf("🔌 Push stream attached for %s", reg.ClientID)
+
+	// Drain sendChan onto the push connection until it fails, the client
+	// leaves and the channel is closed, or done is closed because a newer
+	// push connection has taken over.
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		select {
+		case <-done:
+			return
+		case msg, ok := <-client.sendChan:
+			if !ok {
+				return
+			}
+			if err := writeFrame(conn, pushFrame{Content: msg}); err != nil {
+				log.Printf("⚠️ Push write failed for %s: %v", reg.ClientID, err)
+				client.pushMu.Lock()
+				superseded := client.pushDone != done
+				client.pushMu.Unlock()
+				if !superseded {
+					c.dropClient(reg.ClientID)
+				}
+				return
+			}
+		}
+	}
+}
+
+// dropClient removes a client whose push stream has failed, mirroring Leave
+// without requiring the client to have sent one.
+func (c *ChatServer) dropClient(clientID string) {
+	c.mu.Lock()
+	client, exists := c.clients[clientID]
+	var room string
+	if exists {
+		room = client.room
+		c.removeFromRoomLocked(client)
+		delete(c.clients, clientID)
+	}
+	numClients := len(c.clients)
+	c.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	closePushConn(client)
+	close(client.sendChan)
+
+	if c.Cluster != nil {
+		if err := c.Cluster.UnregisterClient(clientID); err != nil {
+			log.Printf("⚠️ Failed to unregister %s from cluster: %v", clientID, err)
+		}
+	}
+
+	log.Printf("👋 User %s dropped! Total clients: %d", clientID, numClients)
+	leaveMsg := fmt.Sprintf("📢 User %s left", clientID)
+	if room != "" {
+		c.broadcastToRoom(room, clientID, leaveMsg)
+	}
+}
+
+func closePushConn(client *Client) {
+	client.pushMu.Lock()
+	if client.pushConn != nil {
+		client.pushConn.Close()
+	}
+	client.pushMu.Unlock()
+}
+
+// writeFrame writes a length-prefixed JSON encoding of v.
+func writeFrame(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readFrame reads a length-prefixed JSON frame written by writeFrame into v.
+func readFrame(r io.Reader, v interface{}) error {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// joinCluster registers this node in etcd and wires its broadcast watch up to
+// the server's local fanout, skipping messages this node itself published.
+func (c *ChatServer) joinCluster(endpoints []string) error {
+	nodeCluster, err := cluster.New(c.NodeID, endpoints)
+	if err != nil {
+		return err
+	}
+	c.Cluster = nodeCluster
+	c.Cluster.Watch(func(msg cluster.BroadcastMessage) {
+		displayMsg := fmt.Sprintf("[%s]: %s", msg.SenderName, msg.Content)
+		c.broadcastToRoom(msg.Room, msg.SenderID, displayMsg)
+
+		// Persist cluster-relayed messages too, since broadcastChat only
+		// appends to History for locally-originated ones; otherwise a node's
+		// replay-on-join and GetHistory never reflect messages that
+		// originated elsewhere in the cluster. Store the raw Content, not
+		// displayMsg, to match how locally-originated messages are stored.
+		entry := Message{
+			ClientID: msg.SenderID,
+			Content:  msg.Content,
+			Type:     MsgTypeChat,
+			Target:   msg.Room,
+			TS:       msg.TS,
+		}
+		if err := c.History.Append(msg.Room, entry); err != nil {
+			log.Printf("⚠️ Failed to append cluster-relayed history for room %s: %v", msg.Room, err)
+		}
+	})
+	return nil
+}
+
+// historyCapPerRoom bounds the default in-memory ring buffer.
+const historyCapPerRoom = 500
+
+// listen opens addr as plain TCP, or as TLS when both cert and key paths are
+// set.
+func listen(addr, certFile, keyFile string) (net.Listener, error) {
+	if certFile == "" || keyFile == "" {
+		log.Printf("⚠️ Running %s without TLS (no --tls-cert/--tls-key given)", addr)
+		return net.Listen("tcp", addr)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS keypair: %w", err)
+	}
+	return tls.Listen("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+func main() {
+	etcdEndpoints := flag.String("etcd-endpoints", "", "comma-separated etcd endpoints; enables multi-node clustering when set")
+	sqliteHistoryPath := flag.String("history-db", "", "path to a SQLite database for message history; defaults to an in-memory ring buffer when unset")
+	tlsCert := flag.String("tls-cert", "", "path to a TLS certificate; enables TLS with --tls-key")
+	tlsKey := flag.String("tls-key", "", "path to a TLS private key; enables TLS with --tls-cert")
+	tokenFile := flag.String("token-file", "", "path to a token:clientID file for authenticating Join")
+	jwtSecret := flag.String("jwt-secret", "", "HMAC secret for validating JWT Join tokens; the sub claim becomes the ClientID")
+	adminToken := flag.String("admin-token", "", "shared secret required by the Kick RPC; Kick is disabled when unset")
+	flag.Parse()
+
+	hostname, _ := os.Hostname()
+	nodeID := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	var history HistoryStore
+	if *sqliteHistoryPath != "" {
+		store, err := newSQLiteHistoryStore(*sqliteHistoryPath)
+		if err != nil {
+			log.Fatal("Error opening history database:", err)
+		}
+		defer store.Close()
+		history = store
+		log.Printf("🗄️ Persisting history to %s", *sqliteHistoryPath)
+	} else {
+		history = newMemoryHistoryStore(historyCapPerRoom)
+	}
+
+	var auth Authenticator
+	switch {
+	case *tokenFile != "":
+		a, err := newStaticTokenAuthenticator(*tokenFile)
+		if err != nil {
+			log.Fatal("Error loading token file:", err)
+		}
+		auth = a
+		log.Printf("🔐 Authenticating Join against %s", *tokenFile)
+	case *jwtSecret != "":
+		auth = newJWTAuthenticator(*jwtSecret)
+		log.Println("🔐 Authenticating Join against HMAC-signed JWTs")
+	default:
+		log.Println("⚠️ Running without Join authentication (no --token-file/--jwt-secret given)")
+	}
+
+	sessionSecret := make([]byte, 32)
+	if _, err := rand.Read(sessionSecret); err != nil {
+		log.Fatal("Error generating session secret:", err)
+	}
+
+	// Create chat server
+	chatServer := &ChatServer{
+		clients:       make(map[string]*Client),
+		rooms:         make(map[string]map[string]*Client),
+		nextID:        1,
+		NodeID:        nodeID,
+		History:       history,
+		Auth:          auth,
+		AdminToken:    *adminToken,
+		sessionSecret: sessionSecret,
+	}
+
+	if *etcdEndpoints != "" {
+		if err := chatServer.joinCluster(strings.Split(*etcdEndpoints, ",")); err != nil {
+			log.Fatal("Error joining cluster:", err)
+		}
+		defer chatServer.Cluster.Close()
+		log.Printf("🌐 Clustering enabled as node %s via %s", nodeID, *etcdEndpoints)
+	}
+
+	// Register RPC service
+	rpc.Register(chatServer)
+
+	// Start listening for TCP connections
+	listener, err := listen(":1234", *tlsCert, *tlsKey)
+	if err != nil {
+		log.Fatal("Error starting server:", err)
+	}
+	defer listener.Close()
+
+	// Start listening for push connections, which carry streamed broadcasts
+	pushListener, err := listen(pushAddr, *tlsCert, *tlsKey)
+	if err != nil {
+		log.Fatal("Error starting push listener:", err)
+	}
+	defer pushListener.Close()
+	go chatServer.servePush(pushListener)
+
+	log.Println("🚀 Real-time Chat Server is running on port 1234...")
+	log.Println("📡 Push stream listening on port 1235...")
+	log.Println("Waiting for clients to connect...")
+
+	// Accept client connections continuously
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Println("Connection error:", err)
+			continue
+		}
+		// Handle each client connection in a separate goroutine
+		go rpc.ServeConn(conn)
+	}
+}